@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiHand is the JSON view of one of a seat's hands.
+type apiHand struct {
+	Cards       []card `json:"cards"`
+	Score       int    `json:"score"`
+	Bet         int    `json:"bet"`
+	Bust        bool   `json:"bust"`
+	Stand       bool   `json:"stand"`
+	Doubled     bool   `json:"doubled"`
+	Surrendered bool   `json:"surrendered"`
+	Message     string `json:"message,omitempty"`
+}
+
+// apiSeat is the JSON view of a seat: it never exposes another player's
+// identity beyond whether it's the caller's own seat.
+type apiSeat struct {
+	You         bool      `json:"you"`
+	Hands       []apiHand `json:"hands"`
+	CurrentHand int       `json:"currentHand"`
+	Ready       bool      `json:"ready"`
+	Chips       int       `json:"chips"`
+	CurrentBet  int       `json:"currentBet"`
+	Insurance   int       `json:"insurance,omitempty"`
+	Wins        int       `json:"wins"`
+	Losses      int       `json:"losses"`
+	MatchOver   bool      `json:"matchOver"`
+	Message     string    `json:"message,omitempty"`
+}
+
+// apiGameState is the JSON serialization of a GameState. The dealer's hole
+// card is only included once the round is done (or the match is over).
+type apiGameState struct {
+	GameID      string    `json:"gameId"`
+	Version     int       `json:"version"`
+	Phase       Phase     `json:"phase"`
+	BestOf      int       `json:"bestOf"`
+	Seats       []apiSeat `json:"seats"`
+	DealerHand  []card    `json:"dealerHand"`
+	DealerScore int       `json:"dealerScore,omitempty"`
+	CurrentSeat int       `json:"currentSeat"`
+	Message     string    `json:"message,omitempty"`
+}
+
+func toAPIGameState(gameID, player string, state *GameState) apiGameState {
+	out := apiGameState{
+		GameID:      gameID,
+		Version:     state.Version,
+		Phase:       state.Phase,
+		BestOf:      state.BestOf,
+		CurrentSeat: state.CurrentSeat,
+		Message:     state.Message,
+	}
+
+	for _, s := range state.Seats {
+		seat := apiSeat{
+			You:         s.PlayerID == player,
+			CurrentHand: s.CurrentHand,
+			Ready:       s.Ready,
+			Chips:       s.Chips,
+			CurrentBet:  s.CurrentBet,
+			Insurance:   s.Insurance,
+			Wins:        s.Wins,
+			Losses:      s.Losses,
+			MatchOver:   s.MatchOver,
+			Message:     s.Message,
+		}
+		for _, h := range s.Hands {
+			seat.Hands = append(seat.Hands, apiHand{
+				Cards:       h.Cards,
+				Score:       score(h.Cards),
+				Bet:         h.Bet,
+				Bust:        h.Bust,
+				Stand:       h.Stand,
+				Doubled:     h.Doubled,
+				Surrendered: h.Surrendered,
+				Message:     h.Message,
+			})
+		}
+		out.Seats = append(out.Seats, seat)
+	}
+
+	if state.Phase == PhaseDone || state.Phase == PhaseMatchOver {
+		out.DealerHand = state.DealerHand
+		out.DealerScore = score(state.DealerHand)
+	} else if len(state.DealerHand) > 0 {
+		out.DealerHand = state.DealerHand[:1]
+	}
+
+	return out
+}
+
+// wantsJSON applies basic content negotiation so bots and TUIs can drive
+// games through the same URLs a browser uses, without a dedicated Accept
+// header forcing every caller onto /api.
+func wantsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/html")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func fetchState(session *GameSession, player string) *GameState {
+	response := make(chan *GameState)
+	session.commands <- GameCommand{Action: "", PlayerID: player, Response: response}
+	select {
+	case state := <-response:
+		return state
+	case <-time.After(2 * time.Second):
+		return nil
+	}
+}
+
+// apiCreateGame handles POST /api/games: creates a table, seats the caller,
+// and returns the initial state.
+func apiCreateGame(w http.ResponseWriter, r *http.Request) {
+	tableID, session := createTable()
+	player := playerID(w, r)
+
+	response := make(chan *GameState)
+	session.commands <- GameCommand{Action: "join", PlayerID: player, Response: response}
+	state := <-response
+
+	writeJSON(w, http.StatusCreated, toAPIGameState(tableID, player, state))
+}
+
+// apiGetGame handles GET /api/games/{game}.
+func apiGetGame(w http.ResponseWriter, r *http.Request) {
+	tableID := r.PathValue("game")
+	if !passphrase.MatchString(tableID) {
+		http.Error(w, "Invalid table passphrase", http.StatusBadRequest)
+		return
+	}
+	session, exists := getSession(tableID)
+	if !exists {
+		http.Error(w, "Table not found", http.StatusNotFound)
+		return
+	}
+
+	player := playerID(w, r)
+	state := fetchState(session, player)
+	if state == nil {
+		http.Error(w, "Game timeout", http.StatusGatewayTimeout)
+		return
+	}
+	w.Header().Set("X-Game-Version", strconv.Itoa(state.Version))
+	writeJSON(w, http.StatusOK, toAPIGameState(tableID, player, state))
+}
+
+type actionRequest struct {
+	Action string `json:"action"`
+	Amount int    `json:"amount,omitempty"`
+}
+
+// apiPostAction handles POST /api/games/{game}/actions. The caller may send
+// X-Game-Version with the version it last observed; if the table has since
+// advanced, the action is rejected with 409 instead of being applied blind.
+// The check and the action are a single round trip through the actor, so
+// nothing else can advance Version in between.
+func apiPostAction(w http.ResponseWriter, r *http.Request) {
+	tableID := r.PathValue("game")
+	if !passphrase.MatchString(tableID) {
+		http.Error(w, "Invalid table passphrase", http.StatusBadRequest)
+		return
+	}
+	session, exists := getSession(tableID)
+	if !exists {
+		http.Error(w, "Table not found", http.StatusNotFound)
+		return
+	}
+
+	var req actionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	player := playerID(w, r)
+	cmd := GameCommand{Action: req.Action, PlayerID: player, Amount: req.Amount}
+
+	if known := r.Header.Get("X-Game-Version"); known != "" {
+		wantVersion, err := strconv.Atoi(known)
+		if err != nil {
+			http.Error(w, "Invalid X-Game-Version", http.StatusBadRequest)
+			return
+		}
+		cmd.WantVersion = &wantVersion
+	}
+
+	response := make(chan *GameState)
+	conflict := make(chan bool, 1)
+	cmd.Response = response
+	cmd.Conflict = conflict
+	session.commands <- cmd
+	state := <-response
+
+	out := toAPIGameState(tableID, player, state)
+	w.Header().Set("X-Game-Version", strconv.Itoa(state.Version))
+	if <-conflict {
+		writeJSON(w, http.StatusConflict, out)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// apiGameStats handles GET /api/games/{game}/stats: the live scoreboard for
+// an in-progress or finished table.
+func apiGameStats(w http.ResponseWriter, r *http.Request) {
+	tableID := r.PathValue("game")
+	if !passphrase.MatchString(tableID) {
+		http.Error(w, "Invalid table passphrase", http.StatusBadRequest)
+		return
+	}
+	session, exists := getSession(tableID)
+	if !exists {
+		http.Error(w, "Table not found", http.StatusNotFound)
+		return
+	}
+
+	player := playerID(w, r)
+	state := fetchState(session, player)
+	if state == nil {
+		http.Error(w, "Game timeout", http.StatusGatewayTimeout)
+		return
+	}
+	w.Header().Set("X-Game-Version", strconv.Itoa(state.Version))
+	writeJSON(w, http.StatusOK, toAPIGameState(tableID, player, state))
+}
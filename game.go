@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Card represents a playing card with rank and suit
+type card int
+
+var (
+	values = []string{"2", "3", "4", "5", "6", "7", "8", "9", "10", "Jack", "Queen", "King", "Ace"}
+	scores = []int{2, 3, 4, 5, 6, 7, 8, 9, 10, 10, 10, 10, 11}
+	suits  = []string{"spades", "hearts", "diamonds", "clubs"}
+)
+
+func init() {
+	rand.Seed(time.Now().UnixNano())
+}
+
+func (c card) String() string {
+	return fmt.Sprintf("%v of %v", values[int(c)%len(values)], suits[int(c)%len(suits)])
+}
+
+func (c card) score() int {
+	return scores[int(c)%len(scores)]
+}
+
+// score calculates the best possible score for a hand
+func score(hand []card) int {
+	var total, aces int
+	for _, c := range hand {
+		s := c.score()
+		total += s
+		if s == 11 {
+			aces++
+		}
+	}
+
+	for total > 21 && aces > 0 {
+		total -= 10
+		aces--
+	}
+	return total
+}
+
+func isBlackjack(hand []card) bool {
+	return len(hand) == 2 && score(hand) == 21
+}
+
+func hasAce(hand []card) bool {
+	for _, c := range hand {
+		if c.score() == 11 {
+			return true
+		}
+	}
+	return false
+}
+
+func newDeck() []card {
+	deck := make([]card, 52)
+	for i := range deck {
+		deck[i] = card(i)
+	}
+	rand.Shuffle(len(deck), func(i, j int) { deck[i], deck[j] = deck[j], deck[i] })
+	return deck
+}
@@ -0,0 +1,121 @@
+package main
+
+// rank returns a card's rank index (its position in `values`), independent
+// of suit, so two cards can be compared for a pair regardless of suit.
+func rank(c card) int {
+	return int(c) % len(values)
+}
+
+// aceRank is the rank index of an Ace, the last entry in `values`.
+var aceRank = len(values) - 1
+
+func isAce(c card) bool {
+	return rank(c) == aceRank
+}
+
+// currentHand returns the hand a seat is presently acting on, along with
+// whether it's legal for cmd.PlayerID to act on it right now.
+func currentHand(state *GameState, playerID string) (*Seat, *Hand, bool) {
+	if state.Phase != PhasePlaying {
+		return nil, nil, false
+	}
+	i := seatIndex(state, playerID)
+	if i == -1 || i != state.CurrentSeat {
+		return nil, nil, false
+	}
+	seat := &state.Seats[i]
+	hand := &seat.Hands[seat.CurrentHand]
+	return seat, hand, true
+}
+
+// doubleDown doubles a hand's wager and deals exactly one more card, ending
+// the hand regardless of the result. The len(hand.Cards) != 2 guard only
+// ever passes on the untouched starting hand, since a hit grows it past two
+// and double/split/surrender all end it or replace it outright.
+func doubleDown(state *GameState, playerID string) bool {
+	seat, hand, ok := currentHand(state, playerID)
+	if !ok || len(hand.Cards) != 2 || seat.Chips < hand.Bet {
+		return false
+	}
+
+	seat.Chips -= hand.Bet
+	hand.Bet *= 2
+	hand.Doubled = true
+	hand.Cards = append(hand.Cards, drawCard(state))
+	if score(hand.Cards) > 21 {
+		hand.Bust = true
+	}
+	hand.Stand = true
+	advanceHandOrSeat(state)
+	return true
+}
+
+// splitHand turns a starting pair into two hands, each drawing one new
+// card, played one after the other. Re-splitting is not supported. The
+// rank comparison runs on the seat's actual starting cards, not whatever a
+// hit happened to draw, since len(hand.Cards) == 2 only holds before the
+// seat has acted.
+func splitHand(state *GameState, playerID string) bool {
+	seat, hand, ok := currentHand(state, playerID)
+	if !ok || len(seat.Hands) != 1 || len(hand.Cards) != 2 {
+		return false
+	}
+	if rank(hand.Cards[0]) != rank(hand.Cards[1]) {
+		return false
+	}
+	if seat.Chips < hand.Bet {
+		return false
+	}
+
+	seat.Chips -= hand.Bet
+	second := Hand{Cards: []card{hand.Cards[1]}, Bet: hand.Bet, FromSplit: true}
+	hand.Cards = hand.Cards[:1]
+	hand.FromSplit = true
+
+	hand.Cards = append(hand.Cards, drawCard(state))
+	second.Cards = append(second.Cards, drawCard(state))
+
+	seat.Hands = []Hand{*hand, second}
+	seat.CurrentHand = 0
+	return true
+}
+
+// surrenderHand forfeits half the hand's bet before any card is drawn,
+// refunding the other half, and ends the hand.
+func surrenderHand(state *GameState, playerID string) bool {
+	seat, hand, ok := currentHand(state, playerID)
+	if !ok || len(hand.Cards) != 2 {
+		return false
+	}
+
+	seat.Chips += hand.Bet / 2
+	hand.Surrendered = true
+	hand.Message = "Surrendered"
+	advanceHandOrSeat(state)
+	return true
+}
+
+// takeInsurance offers a side bet, up to half the original wager, against
+// the dealer holding blackjack. It's only on the table while the dealer's
+// up card is an Ace and before anyone has acted.
+func takeInsurance(state *GameState, playerID string, amount int) bool {
+	if state.Phase != PhasePlaying || len(state.DealerHand) == 0 || !isAce(state.DealerHand[0]) {
+		return false
+	}
+	i := seatIndex(state, playerID)
+	if i == -1 {
+		return false
+	}
+	seat := &state.Seats[i]
+	if seat.Insurance != 0 || len(seat.Hands) == 0 {
+		return false
+	}
+	maxInsurance := seat.Hands[0].Bet / 2
+	if amount <= 0 || amount > maxInsurance || amount > seat.Chips {
+		return false
+	}
+
+	seat.Chips -= amount
+	seat.Insurance = amount
+	return true
+}
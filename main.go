@@ -1,269 +1,256 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"html/template"
-	"math/rand"
+	"log"
 	"net/http"
-	"regexp"
-	"sync"
+	"strconv"
 	"time"
 )
 
-// Card represents a playing card with rank and suit
-type card int
-
-// GameState contains all game state information
-type GameState struct {
-	PlayerHand []card
-	DealerHand []card
-	Deck       []card
-	Bust       bool
-	Stand      bool
-	Message    string
-}
-
-// GameCommand represents actions sent to game goroutine
-type GameCommand struct {
-	Action   string            // Message type
-	Response chan<- *GameState // Reply channel
-}
-
-// GameSession manages communication with game goroutine
-type GameSession struct {
-	commands chan GameCommand // Message queue
-	created  time.Time        // Actor state
-}
-
 var (
-	values = []string{"2", "3", "4", "5", "6", "7", "8", "9", "10", "Jack", "Queen", "King", "Ace"}
-	scores = []int{2, 3, 4, 5, 6, 7, 8, 9, 10, 10, 10, 10, 11}
-	suits  = []string{"spades", "hearts", "diamonds", "clubs"}
-
-	games     = make(map[string]*GameSession)
-	gameMutex sync.RWMutex
-	gameIDRe  = regexp.MustCompile(`^game-\d+$`)
-	funcMap   = template.FuncMap{
+	funcMap = template.FuncMap{
 		"score": score}
 
 	templates = template.Must(template.New("").Funcs(funcMap).Parse(`
 	{{define "game"}}
 	<html><body>
-		<h1>Blackjack</h1>
-		{{if .Message}}<p style="color:red">{{.Message}}</p>{{end}}
+		<h1>Blackjack &mdash; Table {{.GameID}}</h1>
+		<p>Share this link to invite others: <code>/join/{{.GameID}}</code></p>
+		<p id="message" style="color:red">{{.Message}}</p>
+
 		<h2>Dealer's Hand</h2>
-		<p>{{index .DealerHand 0}} + ???</p>
-		
-		<h2>Your Hand ({{score .PlayerHand}})</h2>
-		{{range .PlayerHand}}<p>{{.}}</p>{{end}}
-		
-		{{if not .Stand}}
-		<a href="/game/{{.GameID}}/hit">Hit</a>
-		<a href="/game/{{.GameID}}/stand">Stand</a>
-		{{else}}
-		<h2>Dealer's Full Hand ({{score .DealerHand}})</h2>
-		{{range .DealerHand}}<p>{{.}}</p>{{end}}
-		<h3>{{.Message}}</h3>
-		<a href="/">New Game</a>
+		<ul id="dealer-hand">
+		{{if eq .Phase "waiting"}}<li>(waiting for players)</li>
+		{{else if eq .Phase "playing"}}<li>{{index .DealerHand 0}}</li><li id="dealer-hole">???</li>
+		{{else}}{{range .DealerHand}}<li>{{.}}</li>{{end}}
+		{{end}}
+		</ul>
+
+		<h2>Seats (best of {{.BestOf}})</h2>
+		<ol id="seats">
+		{{range $i, $seat := .Seats}}
+			<li{{if eq $seat.PlayerID $.PlayerID}} style="font-weight:bold"{{end}}>
+				{{if eq $seat.PlayerID $.PlayerID}}You{{else}}Player {{$i}}{{end}}
+				&mdash; {{$seat.Chips}} chips, record {{$seat.Wins}}-{{$seat.Losses}}
+				{{if $seat.Insurance}}, insurance {{$seat.Insurance}}{{end}}
+				{{if $.IsWaiting}}&mdash; {{if $seat.Ready}}ready{{else}}not ready{{end}}
+				{{else if eq $.Phase "betting"}}&mdash; {{if $seat.BetPlaced}}bet {{$seat.CurrentBet}}{{else}}no bet yet{{end}}
+				{{else}}
+					{{range $h, $hand := $seat.Hands}}
+					<div>hand {{$h}} (bet {{$hand.Bet}}, {{score $hand.Cards}}) {{range $hand.Cards}}{{.}}; {{end}} {{$hand.Message}}</div>
+					{{end}}
+				{{end}}
+			</li>
+		{{end}}
+		</ol>
+
+		<div id="actions">
+		{{if .IsWaiting}}
+		<button id="join" onclick="send('join')">Join</button>
+		<button id="ready" onclick="send('ready')">Ready</button>
+		{{else if eq .Phase "betting"}}
+		<form action="/game/{{.GameID}}/bet" method="post">
+			<input type="number" name="amount" min="1" value="10">
+			<button type="submit">Bet</button>
+		</form>
+		{{else if eq .Phase "playing"}}
+			{{if .CanInsurance}}
+			<button id="insurance" onclick="insure()">Insurance</button>
+			{{end}}
+			{{if .IsYourTurn}}
+			<button id="hit" onclick="send('hit')">Hit</button>
+			<button id="stand" onclick="send('stand')">Stand</button>
+			{{if .CanDouble}}<button id="double" onclick="send('double')">Double Down</button>{{end}}
+			{{if .CanSplit}}<button id="split" onclick="send('split')">Split</button>{{end}}
+			{{if .CanSurrender}}<button id="surrender" onclick="send('surrender')">Surrender</button>{{end}}
+			{{else}}<p>Waiting for another seat...</p>
+			{{end}}
+		{{else if eq .Phase "done"}}
+		<button id="next_round" onclick="send('next_round')">Next Round</button>
+		{{else if eq .Phase "match_over"}}
+		<button id="new_match" onclick="send('new_match')">New Match</button>
+		<a href="/">New Table</a>
 		{{end}}
+		</div>
+
+		<script>
+		const gameID = "{{.GameID}}";
+		const proto = location.protocol === "https:" ? "wss:" : "ws:";
+		const socket = new WebSocket(proto + "//" + location.host + "/game/" + gameID + "/ws");
+
+		function send(action) {
+			socket.send(JSON.stringify({action: action}));
+		}
+
+		function insure() {
+			const amount = parseInt(prompt("Insurance amount:"), 10);
+			if (amount > 0) socket.send(JSON.stringify({action: "insurance", amount: amount}));
+		}
+
+		socket.onmessage = (msg) => {
+			const evt = JSON.parse(msg.data);
+			if (evt.state) location.reload();
+		};
+		</script>
 	</body></html>
 	{{end}}
 `))
 )
 
-func init() {
-	rand.Seed(time.Now().UnixNano())
-}
-
-func (c card) String() string {
-	return fmt.Sprintf("%v of %v", values[int(c)%len(values)], suits[int(c)%len(suits)])
-}
-
-func (c card) score() int {
-	return scores[int(c)%len(scores)]
-}
-
-// score calculates the best possible score for a hand
-func score(hand []card) int {
-	var total, aces int
-	for _, c := range hand {
-		s := c.score()
-		total += s
-		if s == 11 {
-			aces++
-		}
-	}
+// renderTable fetches the table's current state and renders the game page
+// for the calling player.
+func renderTable(w http.ResponseWriter, r *http.Request, tableID string, session *GameSession, player string) {
+	response := make(chan *GameState)
+	session.commands <- GameCommand{Action: "", PlayerID: player, Response: response}
 
-	for total > 21 && aces > 0 {
-		total -= 10
-		aces--
+	select {
+	case state := <-response:
+		templates.ExecuteTemplate(w, "game", buildGamePage(tableID, player, state))
+	case <-time.After(2 * time.Second):
+		http.Error(w, "Game timeout", http.StatusGatewayTimeout)
 	}
-	return total
 }
 
-func isBlackjack(hand []card) bool {
-	return len(hand) == 2 && score(hand) == 21
+// gamePage is the view model handed to the "game" template: the raw
+// GameState plus everything that depends on who's looking at it.
+type gamePage struct {
+	*GameState
+	GameID       string
+	PlayerID     string
+	IsWaiting    bool
+	IsYourTurn   bool
+	CanDouble    bool
+	CanSplit     bool
+	CanSurrender bool
+	CanInsurance bool
 }
 
-func hasAce(hand []card) bool {
-	for _, c := range hand {
-		if c.score() == 11 {
-			return true
-		}
+func buildGamePage(tableID, player string, state *GameState) gamePage {
+	page := gamePage{
+		GameState: state,
+		GameID:    tableID,
+		PlayerID:  player,
+		IsWaiting: state.Phase == PhaseWaiting,
 	}
-	return false
-}
 
-// gameLoop runs the game state machine
-func gameLoop(initial GameState) *GameSession {
-	session := &GameSession{
-		commands: make(chan GameCommand),
-		created:  time.Now(),
+	i := seatIndex(state, player)
+	if i == -1 || state.Phase != PhasePlaying {
+		return page
 	}
 
-	go func() {
-		state := initial
-		defer close(session.commands)
-
-		for cmd := range session.commands {
-			switch cmd.Action {
-			case "hit":
-				if !state.Stand && !state.Bust {
-					state.PlayerHand = append(state.PlayerHand, state.Deck[0])
-					state.Deck = state.Deck[1:]
-					if score(state.PlayerHand) > 21 {
-						state.Bust = true
-						state.Message = "Bust!"
-					}
-					if isBlackjack(state.PlayerHand) {
-						state.Stand = true
-						state.Message = "Blackjack! You win!"
-					}
-
-				}
-			case "stand":
-				if !state.Stand && !state.Bust {
-					state.Stand = true
-					dealerScore := score(state.DealerHand)
-					// Dealer logic
-					for dealerScore < 17 || (dealerScore == 17 && hasAce(state.DealerHand)) {
-						state.DealerHand = append(state.DealerHand, state.Deck[0])
-						state.Deck = state.Deck[1:]
-						dealerScore = score(state.DealerHand)
-					}
-
-					// Determine winner
-					playerScore := score(state.PlayerHand)
-					switch {
-					case dealerScore > 21:
-						state.Message = "Dealer busts! You win!"
-					case playerScore > dealerScore:
-						state.Message = "You win!"
-					case playerScore == dealerScore:
-						state.Message = "Push!"
-					case playerScore == 21:
-						state.Message = "Blackjack! You win!"
-					default:
-						state.Message = "You lose!"
-					}
-				}
-			}
-			cmd.Response <- &state
-		}
-	}()
+	seat := state.Seats[i]
+	page.CanInsurance = len(state.DealerHand) > 0 && isAce(state.DealerHand[0]) && seat.Insurance == 0 && len(seat.Hands) > 0
+	if i != state.CurrentSeat {
+		return page
+	}
+	page.IsYourTurn = true
 
-	return session
+	hand := seat.Hands[seat.CurrentHand]
+	page.CanDouble = len(hand.Cards) == 2 && seat.Chips >= hand.Bet
+	page.CanSplit = len(seat.Hands) == 1 && len(hand.Cards) == 2 && rank(hand.Cards[0]) == rank(hand.Cards[1]) && seat.Chips >= hand.Bet
+	page.CanSurrender = len(hand.Cards) == 2
+	return page
 }
 
-func getSession(gameID string) (*GameSession, bool) {
-	gameMutex.RLock()
-	defer gameMutex.RUnlock()
-	session, exists := games[gameID]
-	return session, exists
+// indexHandler creates a fresh table, seats the visitor, and redirects them
+// to it.
+func indexHandler(w http.ResponseWriter, r *http.Request) {
+	tableID, session := createTable()
+	player := playerID(w, r)
+	response := make(chan *GameState)
+	session.commands <- GameCommand{Action: "join", PlayerID: player, Response: response}
+	<-response
+	http.Redirect(w, r, "/game/"+tableID, http.StatusSeeOther)
 }
 
-func createGame() (string, *GameSession) {
-	gameID := fmt.Sprintf("game-%d", time.Now().UnixNano())
-	deck := make([]card, 52)
-	for i := range deck {
-		deck[i] = card(i)
+// joinHandler seats the visitor at an existing table via its passphrase.
+func joinHandler(w http.ResponseWriter, r *http.Request) {
+	tableID := r.PathValue("passphrase")
+	if !passphrase.MatchString(tableID) {
+		http.Error(w, "Invalid table passphrase", http.StatusBadRequest)
+		return
 	}
-	rand.Shuffle(len(deck), func(i, j int) { deck[i], deck[j] = deck[j], deck[i] })
 
-	initialState := GameState{
-		PlayerHand: []card{deck[0]},
-		DealerHand: []card{deck[1]},
-		Deck:       deck[2:],
+	session, exists := getSession(tableID)
+	if !exists {
+		http.Error(w, "Table not found", http.StatusNotFound)
+		return
 	}
 
-	session := gameLoop(initialState)
-
-	gameMutex.Lock()
-	defer gameMutex.Unlock()
-	games[gameID] = session
-	return gameID, session
+	player := playerID(w, r)
+	response := make(chan *GameState)
+	session.commands <- GameCommand{Action: "join", PlayerID: player, Response: response}
+	<-response
+	http.Redirect(w, r, "/game/"+tableID, http.StatusSeeOther)
 }
 
-func gameHandler(w http.ResponseWriter, r *http.Request, action string) {
-	gameID := r.PathValue("game")
-	if !gameIDRe.MatchString(gameID) {
-		http.Error(w, "Invalid game ID", http.StatusBadRequest)
+// betHandler handles the plain HTML betting form at POST /game/{id}/bet.
+func betHandler(w http.ResponseWriter, r *http.Request) {
+	tableID := r.PathValue("game")
+	if !passphrase.MatchString(tableID) {
+		http.Error(w, "Invalid table passphrase", http.StatusBadRequest)
 		return
 	}
 
-	session, exists := getSession(gameID)
+	session, exists := getSession(tableID)
 	if !exists {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
 
-	response := make(chan *GameState)
-	session.commands <- GameCommand{Action: action, Response: response}
-
-	select {
-	case state := <-response:
-		data := struct {
-			*GameState
-			GameID string
-		}{
-			GameState: state,
-			GameID:    gameID,
-		}
-		templates.ExecuteTemplate(w, "game", data)
-	case <-time.After(2 * time.Second):
-		http.Error(w, "Game timeout", http.StatusGatewayTimeout)
+	amount, err := strconv.Atoi(r.FormValue("amount"))
+	if err != nil || amount <= 0 {
+		http.Error(w, "Invalid bet amount", http.StatusBadRequest)
+		return
 	}
-}
 
-func hitHandler(w http.ResponseWriter, r *http.Request) {
-	gameHandler(w, r, "hit")
-}
-
-func standHandler(w http.ResponseWriter, r *http.Request) {
-	gameHandler(w, r, "stand")
-}
-
-func newHandler(w http.ResponseWriter, r *http.Request) {
-	gameID, session := createGame()
+	player := playerID(w, r)
 	response := make(chan *GameState)
-	session.commands <- GameCommand{Action: "", Response: response}
-	<-response // Wait for initial state
-	http.Redirect(w, r, "/game/"+gameID+"/hit", http.StatusSeeOther)
+	session.commands <- GameCommand{Action: "bet", PlayerID: player, Amount: amount, Response: response}
+	<-response
+	http.Redirect(w, r, "/game/"+tableID, http.StatusSeeOther)
 }
 
-func cleanupOldGames() {
-	gameMutex.Lock()
-	defer gameMutex.Unlock()
+func gameHandler(w http.ResponseWriter, r *http.Request) {
+	tableID := r.PathValue("game")
+	if !passphrase.MatchString(tableID) {
+		http.Error(w, "Invalid table passphrase", http.StatusBadRequest)
+		return
+	}
+
+	session, exists := getSession(tableID)
+	if !exists {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
 
-	for id, session := range games {
-		if time.Since(session.created) > 30*time.Minute {
-			close(session.commands)
-			delete(games, id)
+	player := playerID(w, r)
+	if wantsJSON(r) {
+		state := fetchState(session, player)
+		if state == nil {
+			http.Error(w, "Game timeout", http.StatusGatewayTimeout)
+			return
 		}
+		writeJSON(w, http.StatusOK, toAPIGameState(tableID, player, state))
+		return
 	}
+	renderTable(w, r, tableID, session, player)
 }
 
 func main() {
+	statsDB := flag.String("stats-db", "", "path to a SQLite database for persistent hand history (defaults to an in-memory ring buffer)")
+	flag.Parse()
+	if *statsDB != "" {
+		store, err := newSQLiteStore(*statsDB)
+		if err != nil {
+			log.Fatalf("opening stats db %q: %v", *statsDB, err)
+		}
+		stats = store
+	}
+
 	go func() {
 		ticker := time.NewTicker(1 * time.Hour)
 		defer ticker.Stop()
@@ -272,12 +259,19 @@ func main() {
 		}
 	}()
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		http.Redirect(w, r, "/new", http.StatusSeeOther)
-	})
-	http.HandleFunc("/new", newHandler)
-	http.HandleFunc("/game/{game}/hit", hitHandler)
-	http.HandleFunc("/game/{game}/stand", standHandler)
+	http.HandleFunc("/", indexHandler)
+	http.HandleFunc("/join/{passphrase}", joinHandler)
+	http.HandleFunc("/game/{game}", gameHandler)
+	http.HandleFunc("/game/{game}/ws", wsHandler)
+	http.HandleFunc("POST /game/{game}/bet", betHandler)
+
+	http.HandleFunc("POST /api/games", apiCreateGame)
+	http.HandleFunc("GET /api/games/{game}", apiGetGame)
+	http.HandleFunc("POST /api/games/{game}/actions", apiPostAction)
+	http.HandleFunc("GET /api/games/{game}/stats", apiGameStats)
+
+	http.HandleFunc("GET /stats/game/{game}", statsGameHandler)
+	http.HandleFunc("GET /stats/player/{playerID}", statsPlayerHandler)
 
 	fmt.Println("Server running on :8080")
 	http.ListenAndServe(":8080", nil)
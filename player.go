@@ -0,0 +1,71 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// playerCookieSecret signs the player-id cookie so a client can't forge
+// another player's seat. It is generated once at process start; restarting
+// the server invalidates outstanding cookies, which is an acceptable
+// tradeoff for this in-memory prototype.
+var playerCookieSecret = randomToken(32)
+
+const playerCookieName = "bj_player"
+
+func randomToken(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func newPlayerID() string {
+	return hex.EncodeToString(randomToken(16))
+}
+
+func signPlayerID(id string) string {
+	mac := hmac.New(sha256.New, playerCookieSecret)
+	mac.Write([]byte(id))
+	return id + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+func verifyPlayerCookie(value string) (string, bool) {
+	id, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return "", false
+	}
+	mac := hmac.New(sha256.New, playerCookieSecret)
+	mac.Write([]byte(id))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return "", false
+	}
+	return id, true
+}
+
+// playerID returns the stable ID for this browser, reattaching a client to
+// its previous seat via a signed cookie and minting a new one if absent or
+// tampered with.
+func playerID(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(playerCookieName); err == nil {
+		if id, ok := verifyPlayerCookie(cookie.Value); ok {
+			return id
+		}
+	}
+
+	id := newPlayerID()
+	http.SetCookie(w, &http.Cookie{
+		Name:     playerCookieName,
+		Value:    signPlayerID(id),
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return id
+}
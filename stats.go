@@ -0,0 +1,148 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Outcome categorizes how a single hand ended, for history and aggregate
+// stats.
+type Outcome string
+
+const (
+	OutcomeWin       Outcome = "win"
+	OutcomeLoss      Outcome = "loss"
+	OutcomePush      Outcome = "push"
+	OutcomeBlackjack Outcome = "blackjack"
+	OutcomeBust      Outcome = "bust"
+	OutcomeSurrender Outcome = "surrender"
+)
+
+// HandRecord is a single completed hand, written once a round resolves.
+type HandRecord struct {
+	GameID      string    `json:"gameId"`
+	PlayerID    string    `json:"playerId"`
+	PlayerCards []card    `json:"playerCards"`
+	DealerCards []card    `json:"dealerCards"`
+	Bet         int       `json:"bet"`
+	Outcome     Outcome   `json:"outcome"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// PlayerStats is a player's lifetime aggregate record across every table
+// they've played.
+type PlayerStats struct {
+	PlayerID    string  `json:"playerId"`
+	HandsPlayed int     `json:"handsPlayed"`
+	Wins        int     `json:"wins"`
+	Losses      int     `json:"losses"`
+	Pushes      int     `json:"pushes"`
+	Blackjacks  int     `json:"blackjacks"`
+	WinRate     float64 `json:"winRate"`
+}
+
+// Store persists completed hands so history and stats survive a table's
+// 30-minute reap window. RecordHand is called once per hand as a round
+// resolves; Flush gives a store a chance to make anything buffered durable
+// before its source table is deleted.
+type Store interface {
+	RecordHand(HandRecord) error
+	HandsForGame(gameID string) []HandRecord
+	PlayerStats(playerID string) PlayerStats
+	Flush() error
+}
+
+// stats is the process-wide store. Swap this for newSQLiteStore to persist
+// history across restarts.
+var stats Store = newMemoryStore(10000)
+
+// recordHand persists one resolved hand. It's called only from
+// resolveRound, the actor loop's terminal branch for a round, so nothing
+// outside the loop ever writes to the store.
+func recordHand(state *GameState, seat *Seat, hand *Hand, outcome Outcome) {
+	stats.RecordHand(HandRecord{
+		GameID:      state.GameID,
+		PlayerID:    seat.PlayerID,
+		PlayerCards: append([]card(nil), hand.Cards...),
+		DealerCards: append([]card(nil), state.DealerHand...),
+		Bet:         hand.Bet,
+		Outcome:     outcome,
+		Timestamp:   time.Now(),
+	})
+}
+
+// memoryStore is a fixed-capacity ring buffer: the simplest Store, trading
+// unbounded history for a bounded memory footprint.
+type memoryStore struct {
+	mu      sync.Mutex
+	records []HandRecord
+	next    int
+	full    bool
+}
+
+func newMemoryStore(capacity int) *memoryStore {
+	return &memoryStore{records: make([]HandRecord, capacity)}
+}
+
+func (m *memoryStore) RecordHand(r HandRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[m.next] = r
+	m.next = (m.next + 1) % len(m.records)
+	if m.next == 0 {
+		m.full = true
+	}
+	return nil
+}
+
+// all returns every stored record, oldest first.
+func (m *memoryStore) all() []HandRecord {
+	if !m.full {
+		return append([]HandRecord(nil), m.records[:m.next]...)
+	}
+	out := append([]HandRecord(nil), m.records[m.next:]...)
+	return append(out, m.records[:m.next]...)
+}
+
+func (m *memoryStore) HandsForGame(gameID string) []HandRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []HandRecord
+	for _, r := range m.all() {
+		if r.GameID == gameID {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func (m *memoryStore) PlayerStats(playerID string) PlayerStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := PlayerStats{PlayerID: playerID}
+	for _, r := range m.all() {
+		if r.PlayerID != playerID {
+			continue
+		}
+		out.HandsPlayed++
+		switch r.Outcome {
+		case OutcomeWin:
+			out.Wins++
+		case OutcomeBlackjack:
+			out.Wins++
+			out.Blackjacks++
+		case OutcomePush:
+			out.Pushes++
+		default:
+			out.Losses++
+		}
+	}
+	if out.HandsPlayed > 0 {
+		out.WinRate = float64(out.Wins) / float64(out.HandsPlayed)
+	}
+	return out
+}
+
+// Flush is a no-op for the in-memory store: every RecordHand call is
+// already durable for the life of the process.
+func (m *memoryStore) Flush() error { return nil }
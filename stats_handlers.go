@@ -0,0 +1,21 @@
+package main
+
+import "net/http"
+
+// statsGameHandler handles GET /stats/game/{game}: every completed hand for
+// one table, in play order, regardless of whether the table is still live.
+func statsGameHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := r.PathValue("game")
+	if !passphrase.MatchString(gameID) {
+		http.Error(w, "Invalid table passphrase", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats.HandsForGame(gameID))
+}
+
+// statsPlayerHandler handles GET /stats/player/{playerID}: a player's
+// lifetime aggregate record across every table they've played.
+func statsPlayerHandler(w http.ResponseWriter, r *http.Request) {
+	playerID := r.PathValue("playerID")
+	writeJSON(w, http.StatusOK, stats.PlayerStats(playerID))
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteStore is a Store backed by a SQLite database, for deployments that
+// want history to survive a process restart.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// newSQLiteStore opens (creating if needed) a SQLite database at path and
+// ensures its schema exists.
+func newSQLiteStore(path string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS hands (
+			game_id      TEXT NOT NULL,
+			player_id    TEXT NOT NULL,
+			player_cards TEXT NOT NULL,
+			dealer_cards TEXT NOT NULL,
+			bet          INTEGER NOT NULL,
+			outcome      TEXT NOT NULL,
+			played_at    DATETIME NOT NULL
+		)`); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+func (s *sqliteStore) RecordHand(r HandRecord) error {
+	playerCards, err := json.Marshal(r.PlayerCards)
+	if err != nil {
+		return err
+	}
+	dealerCards, err := json.Marshal(r.DealerCards)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO hands (game_id, player_id, player_cards, dealer_cards, bet, outcome, played_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		r.GameID, r.PlayerID, string(playerCards), string(dealerCards), r.Bet, string(r.Outcome), r.Timestamp,
+	)
+	return err
+}
+
+func (s *sqliteStore) HandsForGame(gameID string) []HandRecord {
+	rows, err := s.db.Query(
+		`SELECT game_id, player_id, player_cards, dealer_cards, bet, outcome, played_at FROM hands WHERE game_id = ? ORDER BY played_at`,
+		gameID,
+	)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	return scanHandRows(rows)
+}
+
+func (s *sqliteStore) PlayerStats(playerID string) PlayerStats {
+	out := PlayerStats{PlayerID: playerID}
+	rows, err := s.db.Query(`SELECT outcome FROM hands WHERE player_id = ?`, playerID)
+	if err != nil {
+		return out
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var outcome string
+		if err := rows.Scan(&outcome); err != nil {
+			continue
+		}
+		out.HandsPlayed++
+		switch Outcome(outcome) {
+		case OutcomeWin:
+			out.Wins++
+		case OutcomeBlackjack:
+			out.Wins++
+			out.Blackjacks++
+		case OutcomePush:
+			out.Pushes++
+		default:
+			out.Losses++
+		}
+	}
+	if out.HandsPlayed > 0 {
+		out.WinRate = float64(out.Wins) / float64(out.HandsPlayed)
+	}
+	return out
+}
+
+// Flush checkpoints the write-ahead log so recent inserts are durable in
+// the main database file, not just the WAL.
+func (s *sqliteStore) Flush() error {
+	_, err := s.db.Exec(`PRAGMA wal_checkpoint(FULL)`)
+	return err
+}
+
+func scanHandRows(rows *sql.Rows) []HandRecord {
+	var out []HandRecord
+	for rows.Next() {
+		var r HandRecord
+		var playerCards, dealerCards, outcome string
+		if err := rows.Scan(&r.GameID, &r.PlayerID, &playerCards, &dealerCards, &r.Bet, &outcome, &r.Timestamp); err != nil {
+			continue
+		}
+		json.Unmarshal([]byte(playerCards), &r.PlayerCards)
+		json.Unmarshal([]byte(dealerCards), &r.DealerCards)
+		r.Outcome = Outcome(outcome)
+		out = append(out, r)
+	}
+	return out
+}
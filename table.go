@@ -0,0 +1,623 @@
+package main
+
+import (
+	"math/rand"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxSeats      = 6
+	startingChips = 100
+	defaultBestOf = 5
+)
+
+// Phase tracks where a table is in its round lifecycle.
+type Phase string
+
+const (
+	PhaseWaiting   Phase = "waiting"
+	PhaseBetting   Phase = "betting"
+	PhasePlaying   Phase = "playing"
+	PhaseDone      Phase = "done"
+	PhaseMatchOver Phase = "match_over"
+)
+
+// Hand is one hand a seat is playing. A seat normally plays a single hand;
+// splitting a pair gives it a second one, played to completion in order.
+type Hand struct {
+	Cards       []card
+	Bet         int
+	Bust        bool
+	Stand       bool
+	Doubled     bool
+	Surrendered bool
+	FromSplit   bool // a split pair hitting 21 is just 21, not a blackjack bonus
+	Message     string
+}
+
+// Seat is one seated player's hands, lobby, and match state. Chips and the
+// match win/loss record persist across rounds within the same match; Ready,
+// Hands, CurrentHand and CurrentBet reset every round.
+type Seat struct {
+	PlayerID    string
+	Hands       []Hand
+	CurrentHand int
+	Ready       bool
+	Message     string
+	Chips       int
+	CurrentBet  int
+	BetPlaced   bool
+	Insurance   int
+	Wins        int
+	Losses      int
+	MatchOver   bool
+}
+
+// handDone reports whether a hand has nothing left to play.
+func (h Hand) handDone() bool {
+	return h.Bust || h.Stand || h.Surrendered
+}
+
+// seatDone reports whether every one of a seat's hands has finished.
+func (s Seat) seatDone() bool {
+	for _, h := range s.Hands {
+		if !h.handDone() {
+			return false
+		}
+	}
+	return true
+}
+
+// GameState contains all game state information for a table. Version is a
+// monotonic counter bumped on every mutation so API clients can detect
+// stale reads before submitting an action.
+type GameState struct {
+	GameID      string
+	Seats       []Seat
+	DealerHand  []card
+	Deck        []card
+	CurrentSeat int
+	Phase       Phase
+	Message     string
+	Version     int
+	BestOf      int
+}
+
+// clone returns a deep copy of the state, safe to hand to a goroutine that
+// outlives this command — the actor goroutine keeps mutating the original
+// (including slices nested in Seats and Hands) for the life of the table.
+func (s *GameState) clone() *GameState {
+	out := *s
+	out.Seats = make([]Seat, len(s.Seats))
+	for i, seat := range s.Seats {
+		seat.Hands = append([]Hand(nil), seat.Hands...)
+		out.Seats[i] = seat
+	}
+	out.DealerHand = append([]card(nil), s.DealerHand...)
+	out.Deck = append([]card(nil), s.Deck...)
+	return &out
+}
+
+// EventType identifies the kind of broadcast event published by a GameSession.
+type EventType string
+
+const (
+	EventPlayerJoined  EventType = "player_joined"
+	EventPlayerLeft    EventType = "player_left"
+	EventDealerDealing EventType = "dealer_dealing"
+	EventHandUpdated   EventType = "hand_updated"
+	EventRoundResult   EventType = "round_result"
+	EventMatchOver     EventType = "match_over"
+)
+
+// Event is a single message pushed to every subscriber of a GameSession.
+type Event struct {
+	Type    EventType  `json:"type"`
+	GameID  string     `json:"gameId"`
+	State   *GameState `json:"state,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+// GameCommand represents actions sent to the game goroutine.
+type GameCommand struct {
+	Action      string            // hit, stand, bet, next_round, new_match, join, spectate, ready, subscribe, unsubscribe, shutdown
+	PlayerID    string            // seat owner the action is performed as
+	Amount      int               // wager for the bet action
+	WantVersion *int              // if set, the action is rejected as a conflict unless state.Version matches
+	Response    chan<- *GameState // Reply channel for state-changing actions
+	Conflict    chan<- bool       // if set alongside WantVersion, told whether the action was rejected as stale
+	Subscriber  chan<- Event      // Registered by subscribe/unsubscribe actions
+}
+
+// GameSession manages communication with the table's game goroutine.
+type GameSession struct {
+	commands chan GameCommand // Message queue
+	created  time.Time        // Actor state
+}
+
+var (
+	games      = make(map[string]*GameSession)
+	gameMutex  sync.RWMutex
+	passphrase = regexp.MustCompile(`^[a-z]+-[a-z]+-[a-z]+$`)
+
+	passphraseWords = []string{
+		"amber", "bison", "cedar", "delta", "ember", "falcon", "granite",
+		"harbor", "indigo", "jasper", "kodiak", "lunar", "maple", "nomad",
+		"onyx", "pepper", "quartz", "raven", "summit", "tundra", "umbra",
+		"violet", "willow", "yukon",
+	}
+)
+
+func newPassphrase() string {
+	w := passphraseWords
+	return strings.Join([]string{
+		w[rand.Intn(len(w))],
+		w[rand.Intn(len(w))],
+		w[rand.Intn(len(w))],
+	}, "-")
+}
+
+func seatIndex(state *GameState, playerID string) int {
+	for i := range state.Seats {
+		if state.Seats[i].PlayerID == playerID {
+			return i
+		}
+	}
+	return -1
+}
+
+// nextActiveSeat returns the index of the next seat after `from` that is
+// still playing this round, or -1 once every seat has finished all of its
+// hands or already finished its match.
+func nextActiveSeat(state *GameState, from int) int {
+	for i := from + 1; i < len(state.Seats); i++ {
+		if !state.Seats[i].seatDone() && !state.Seats[i].MatchOver {
+			return i
+		}
+	}
+	return -1
+}
+
+// drawCard returns the next card from the shoe, reshuffling a fresh deck in
+// if it runs dry. Six seats splitting and hitting repeatedly can outlast a
+// single 52-card deck before a round resolves, so every draw after the
+// opening deal goes through here instead of indexing state.Deck directly.
+func drawCard(state *GameState) card {
+	if len(state.Deck) == 0 {
+		state.Deck = newDeck()
+	}
+	c := state.Deck[0]
+	state.Deck = state.Deck[1:]
+	return c
+}
+
+// startRound deals the opening two-card hands once every still-in-match
+// seat has bet: two cards for the dealer (the second stays hidden until the
+// round resolves) and two for each active seat.
+func startRound(state *GameState) {
+	state.Deck = newDeck()
+	state.DealerHand = []card{state.Deck[0], state.Deck[1]}
+	state.Deck = state.Deck[2:]
+	for i := range state.Seats {
+		if state.Seats[i].MatchOver {
+			continue
+		}
+		seat := &state.Seats[i]
+		seat.Hands = []Hand{{Cards: []card{state.Deck[0], state.Deck[1]}, Bet: seat.CurrentBet}}
+		seat.CurrentHand = 0
+		seat.Message = ""
+		state.Deck = state.Deck[2:]
+	}
+	state.Phase = PhasePlaying
+	state.CurrentSeat = -1
+	if first := nextActiveSeat(state, -1); first != -1 {
+		state.CurrentSeat = first
+	} else {
+		resolveRound(state)
+	}
+}
+
+// resolveRound plays out the dealer's hand, settles every seat's bets, and
+// advances (or ends) each seat's match.
+func resolveRound(state *GameState) {
+	dealerScore := score(state.DealerHand)
+	for dealerScore < 17 || (dealerScore == 17 && hasAce(state.DealerHand)) {
+		state.DealerHand = append(state.DealerHand, drawCard(state))
+		dealerScore = score(state.DealerHand)
+	}
+	dealerBlackjack := isBlackjack(state.DealerHand)
+
+	matchesRemaining := false
+	for i := range state.Seats {
+		seat := &state.Seats[i]
+		if seat.MatchOver {
+			continue
+		}
+		settleInsurance(seat, dealerBlackjack)
+		for h := range seat.Hands {
+			hand := &seat.Hands[h]
+			outcome := settleHand(seat, hand, dealerScore, dealerBlackjack)
+			recordHand(state, seat, hand, outcome)
+		}
+		if seat.Chips <= 0 {
+			seat.MatchOver = true
+			seat.Message += " Out of chips — match over."
+		} else {
+			needed := (state.BestOf + 1) / 2
+			if seat.Wins >= needed || seat.Losses >= needed {
+				seat.MatchOver = true
+				if seat.Wins >= needed {
+					seat.Message += " You won the match!"
+				} else {
+					seat.Message += " You lost the match."
+				}
+			}
+		}
+		if !seat.MatchOver {
+			matchesRemaining = true
+		}
+	}
+
+	state.Phase = PhaseDone
+	state.Message = "Round over"
+	if !matchesRemaining {
+		state.Phase = PhaseMatchOver
+		state.Message = "Match over"
+	}
+}
+
+// settleInsurance pays 2:1 when the dealer has blackjack, otherwise the
+// side bet is simply forfeited (it was already deducted from chips).
+func settleInsurance(seat *Seat, dealerBlackjack bool) {
+	if seat.Insurance == 0 {
+		return
+	}
+	if dealerBlackjack {
+		seat.Chips += seat.Insurance * 3
+	}
+	seat.Insurance = 0
+}
+
+// settleHand pays out or collects one hand's bet, records the round's
+// outcome against the seat's match record, and reports that outcome for
+// the stats store. dealerBlackjack forces a push against a player
+// blackjack instead of the usual 3:2 payout — a split hand never counts as
+// a natural, so it can't push against one either.
+func settleHand(seat *Seat, hand *Hand, dealerScore int, dealerBlackjack bool) Outcome {
+	if hand.Surrendered {
+		// Half the bet was already returned when the surrender was taken;
+		// the forfeited half still counts as a loss for the match record.
+		seat.Losses++
+		return OutcomeSurrender
+	}
+	if hand.Bust {
+		hand.Message = "Bust!"
+		seat.Losses++
+		return OutcomeBust
+	}
+
+	playerScore := score(hand.Cards)
+	playerBlackjack := isBlackjack(hand.Cards) && !hand.FromSplit
+	switch {
+	case playerBlackjack && dealerBlackjack:
+		seat.Chips += hand.Bet
+		hand.Message = "Push! Dealer also had blackjack."
+		return OutcomePush
+	case playerBlackjack:
+		seat.Chips += hand.Bet + hand.Bet*3/2
+		seat.Wins++
+		hand.Message = "Blackjack! You win!"
+		return OutcomeBlackjack
+	case dealerScore > 21 || playerScore > dealerScore:
+		seat.Chips += hand.Bet * 2
+		seat.Wins++
+		hand.Message = "You win!"
+		return OutcomeWin
+	case playerScore == dealerScore:
+		seat.Chips += hand.Bet
+		hand.Message = "Push!"
+		return OutcomePush
+	default:
+		seat.Losses++
+		hand.Message = "You lose!"
+		return OutcomeLoss
+	}
+}
+
+// startBetting opens a new betting window for every seat still in the
+// match, clearing the previous round's bet.
+func startBetting(state *GameState) {
+	for i := range state.Seats {
+		if state.Seats[i].MatchOver {
+			continue
+		}
+		state.Seats[i].CurrentBet = 0
+		state.Seats[i].BetPlaced = false
+	}
+	state.Phase = PhaseBetting
+	state.Message = ""
+}
+
+// allBetsPlaced reports whether every seat still in the match has wagered.
+func allBetsPlaced(state *GameState) bool {
+	for _, s := range state.Seats {
+		if !s.MatchOver && !s.BetPlaced {
+			return false
+		}
+	}
+	return true
+}
+
+// allReady reports whether the table has at least one seated player and
+// every seated player has marked themselves ready.
+func allReady(state *GameState) bool {
+	if len(state.Seats) == 0 {
+		return false
+	}
+	for _, s := range state.Seats {
+		if !s.Ready {
+			return false
+		}
+	}
+	return true
+}
+
+// newMatch resets every seat's chips and match record for a fresh
+// best-of-BestOf match, then opens betting.
+func newMatch(state *GameState) {
+	for i := range state.Seats {
+		state.Seats[i] = Seat{PlayerID: state.Seats[i].PlayerID, Ready: true, Chips: startingChips}
+	}
+	startBetting(state)
+}
+
+// advanceHandOrSeat moves to the current seat's next unfinished hand (after
+// a split), or on to the next seat once every hand is finished.
+func advanceHandOrSeat(state *GameState) {
+	seat := &state.Seats[state.CurrentSeat]
+	for i, h := range seat.Hands {
+		if !h.handDone() {
+			seat.CurrentHand = i
+			return
+		}
+	}
+	advanceOrResolve(state)
+}
+
+// gameLoop runs the table's state machine. It is the sole owner of both the
+// game state and the subscriber list, so neither needs a mutex: everything
+// is serialized through the commands channel.
+func gameLoop(tableID string) *GameSession {
+	session := &GameSession{
+		commands: make(chan GameCommand),
+		created:  time.Now(),
+	}
+
+	go func() {
+		state := GameState{GameID: tableID, Phase: PhaseWaiting, BestOf: defaultBestOf}
+		subscribers := make(map[chan<- Event]bool)
+		defer close(session.commands)
+
+		publish := func(evt Event) {
+			evt.GameID = tableID
+			if evt.State != nil {
+				// The actor keeps mutating state in place for the life of the
+				// table; subscribers read evt.State on their own goroutine at
+				// their own pace, so they get a private snapshot instead of a
+				// live, unsynchronized view of it.
+				evt.State = evt.State.clone()
+			}
+			for sub := range subscribers {
+				select {
+				case sub <- evt:
+				default:
+					// Slow subscriber; drop the event rather than block the actor.
+				}
+			}
+		}
+
+		for cmd := range session.commands {
+			if cmd.WantVersion != nil && state.Version != *cmd.WantVersion {
+				// Checked and rejected atomically, in the same command the
+				// actor would otherwise have applied: nothing else can bump
+				// Version between this check and the switch below, since the
+				// actor only ever processes one command at a time.
+				if cmd.Conflict != nil {
+					cmd.Conflict <- true
+				}
+				if cmd.Response != nil {
+					cmd.Response <- state.clone()
+				}
+				continue
+			}
+
+			switch cmd.Action {
+			case "shutdown":
+				// The actor is the only one allowed to close its own
+				// commands channel (the deferred close above does that);
+				// the reaper asks for this instead of closing the channel
+				// itself, which would race this goroutine's own close.
+				return
+			case "subscribe":
+				subscribers[cmd.Subscriber] = true
+				publish(Event{Type: EventPlayerJoined, State: &state})
+			case "unsubscribe":
+				delete(subscribers, cmd.Subscriber)
+				publish(Event{Type: EventPlayerLeft, State: &state})
+			case "spectate":
+				// No seat state change; the subscribe command already
+				// attached the caller to the broadcast stream.
+			case "join":
+				if state.Phase == PhaseWaiting && seatIndex(&state, cmd.PlayerID) == -1 {
+					if len(state.Seats) >= maxSeats {
+						// "Table full" only matters to the caller who just got
+						// turned away; it must not land on state.Message, or
+						// every already-seated player sees a stray banner
+						// until the next phase change clears it.
+						if cmd.Response != nil {
+							rejected := state.clone()
+							rejected.Message = "Table full"
+							cmd.Response <- rejected
+						}
+						continue
+					}
+					state.Seats = append(state.Seats, Seat{PlayerID: cmd.PlayerID, Chips: startingChips})
+					state.Version++
+					publish(Event{Type: EventPlayerJoined, State: &state})
+				}
+			case "ready":
+				if i := seatIndex(&state, cmd.PlayerID); i != -1 && state.Phase == PhaseWaiting {
+					state.Seats[i].Ready = true
+					state.Version++
+					publish(Event{Type: EventHandUpdated, State: &state})
+					if allReady(&state) {
+						startBetting(&state)
+						publish(Event{Type: EventHandUpdated, State: &state})
+					}
+				}
+			case "bet":
+				if i := seatIndex(&state, cmd.PlayerID); i != -1 && state.Phase == PhaseBetting {
+					seat := &state.Seats[i]
+					if !seat.MatchOver && !seat.BetPlaced && cmd.Amount > 0 && cmd.Amount <= seat.Chips {
+						seat.CurrentBet = cmd.Amount
+						seat.Chips -= cmd.Amount
+						seat.BetPlaced = true
+						state.Version++
+						publish(Event{Type: EventHandUpdated, State: &state})
+						if allBetsPlaced(&state) {
+							startRound(&state)
+							publish(Event{Type: EventDealerDealing, State: &state})
+						}
+					}
+				}
+			case "next_round":
+				if state.Phase == PhaseDone {
+					startBetting(&state)
+					state.Version++
+					publish(Event{Type: EventHandUpdated, State: &state})
+				}
+			case "new_match":
+				if state.Phase == PhaseMatchOver {
+					newMatch(&state)
+					state.Version++
+					publish(Event{Type: EventHandUpdated, State: &state})
+				}
+			case "hit":
+				if i := seatIndex(&state, cmd.PlayerID); i != -1 && state.Phase == PhasePlaying && i == state.CurrentSeat {
+					seat := &state.Seats[i]
+					hand := &seat.Hands[seat.CurrentHand]
+					hand.Cards = append(hand.Cards, drawCard(&state))
+					if score(hand.Cards) > 21 {
+						hand.Bust = true
+					}
+					if hand.handDone() {
+						advanceHandOrSeat(&state)
+					}
+					state.Version++
+					publishHandOrResult(publish, &state)
+				}
+			case "stand":
+				if i := seatIndex(&state, cmd.PlayerID); i != -1 && state.Phase == PhasePlaying && i == state.CurrentSeat {
+					seat := &state.Seats[i]
+					seat.Hands[seat.CurrentHand].Stand = true
+					advanceHandOrSeat(&state)
+					state.Version++
+					publishHandOrResult(publish, &state)
+				}
+			case "double":
+				if doubleDown(&state, cmd.PlayerID) {
+					state.Version++
+					publishHandOrResult(publish, &state)
+				}
+			case "split":
+				if splitHand(&state, cmd.PlayerID) {
+					state.Version++
+					publishHandOrResult(publish, &state)
+				}
+			case "surrender":
+				if surrenderHand(&state, cmd.PlayerID) {
+					state.Version++
+					publishHandOrResult(publish, &state)
+				}
+			case "insurance":
+				if takeInsurance(&state, cmd.PlayerID, cmd.Amount) {
+					state.Version++
+					publish(Event{Type: EventHandUpdated, State: &state})
+				}
+			}
+			if cmd.Conflict != nil {
+				cmd.Conflict <- false
+			}
+			if cmd.Response != nil {
+				cmd.Response <- state.clone()
+			}
+		}
+	}()
+
+	return session
+}
+
+// publishHandOrResult broadcasts a hand update, a round result, or a match
+// result, depending on what the last hit/stand resolved into.
+func publishHandOrResult(publish func(Event), state *GameState) {
+	switch state.Phase {
+	case PhaseMatchOver:
+		publish(Event{Type: EventMatchOver, State: state, Message: state.Message})
+	case PhaseDone:
+		publish(Event{Type: EventRoundResult, State: state, Message: state.Message})
+	default:
+		publish(Event{Type: EventHandUpdated, State: state})
+	}
+}
+
+func advanceOrResolve(state *GameState) {
+	if next := nextActiveSeat(state, state.CurrentSeat); next != -1 {
+		state.CurrentSeat = next
+		return
+	}
+	resolveRound(state)
+}
+
+func getSession(tableID string) (*GameSession, bool) {
+	gameMutex.RLock()
+	defer gameMutex.RUnlock()
+	session, exists := games[tableID]
+	return session, exists
+}
+
+// createTable allocates a new table behind a human-shareable passphrase.
+func createTable() (string, *GameSession) {
+	gameMutex.Lock()
+	defer gameMutex.Unlock()
+
+	var id string
+	for {
+		id = newPassphrase()
+		if _, exists := games[id]; !exists {
+			break
+		}
+	}
+
+	session := gameLoop(id)
+	games[id] = session
+	return id, session
+}
+
+// cleanupOldGames reaps tables idle for more than 30 minutes, giving the
+// stats store a chance to flush anything buffered before each one is torn
+// down.
+func cleanupOldGames() {
+	gameMutex.Lock()
+	defer gameMutex.Unlock()
+
+	for id, session := range games {
+		if time.Since(session.created) > 30*time.Minute {
+			stats.Flush()
+			session.commands <- GameCommand{Action: "shutdown"}
+			delete(games, id)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// clientFrame is the JSON payload a browser sends over the socket.
+type clientFrame struct {
+	Action string `json:"action"`
+	Amount int    `json:"amount,omitempty"`
+}
+
+// wsHandler upgrades the connection and bridges it to the GameSession actor:
+// outbound events flow to the socket, inbound frames become GameCommands.
+func wsHandler(w http.ResponseWriter, r *http.Request) {
+	tableID := r.PathValue("game")
+	if !passphrase.MatchString(tableID) {
+		http.Error(w, "Invalid table passphrase", http.StatusBadRequest)
+		return
+	}
+
+	session, exists := getSession(tableID)
+	if !exists {
+		http.Error(w, "Table not found", http.StatusNotFound)
+		return
+	}
+
+	player := playerID(w, r)
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("ws upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	events := make(chan Event, 16)
+	session.commands <- GameCommand{Action: "subscribe", PlayerID: player, Subscriber: events}
+	defer func() { session.commands <- GameCommand{Action: "unsubscribe", PlayerID: player, Subscriber: events} }()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var frame clientFrame
+			if err := conn.ReadJSON(&frame); err != nil {
+				return
+			}
+			response := make(chan *GameState)
+			session.commands <- GameCommand{Action: frame.Action, PlayerID: player, Amount: frame.Amount, Response: response}
+			<-response // state is broadcast via the subscriber channel, not the reply
+		}
+	}()
+
+	for {
+		select {
+		case evt := <-events:
+			if err := conn.WriteJSON(evt); err != nil {
+				return
+			}
+		case <-done:
+			return
+		case <-time.After(30 * time.Second):
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}